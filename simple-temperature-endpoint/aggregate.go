@@ -0,0 +1,211 @@
+package temperature_api
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// AggregateKind selects which aggregate GetAggregate and
+// GetAggregateStepped compute over a range of raw data points.
+type AggregateKind int
+
+const (
+	AggregateMin AggregateKind = iota
+	AggregateMax
+	AggregateMean
+	AggregateSum
+	AggregateCount
+	AggregateStddev
+	AggregateP50
+	AggregateP90
+	AggregateP99
+	// AggregateRate is (last sample - first sample) / elapsed seconds
+	// over the range, mirroring PromQL's rate().
+	AggregateRate
+)
+
+// AggregateSpec selects one aggregate kind to compute.
+type AggregateSpec struct {
+	Kind AggregateKind
+}
+
+// AggregateResult is the outcome of one aggregate computation: Value
+// holds the computed aggregate, and Count is how many raw samples fed
+// into it (0 if the range held no data).
+type AggregateResult struct {
+	Value float64
+	Count int
+}
+
+// AggregateBucket is one bucket of a GetAggregateStepped range query.
+type AggregateBucket struct {
+	Start  time.Time
+	Result AggregateResult
+}
+
+// GetAggregate computes a single AggregateSpec over deviceID's raw
+// samples in [start, end]. Percentiles are estimated in a single pass
+// with the P² streaming quantile algorithm rather than materializing
+// and sorting the range, so the cost stays flat even over large ranges.
+func (server *TemperatureApi) GetAggregate(deviceID string, start, end time.Time, agg AggregateSpec) (AggregateResult, error) {
+	points, err := server.rawRange(deviceID, start, end)
+	if err != nil {
+		return AggregateResult{}, err
+	}
+
+	return computeAggregate(points, agg), nil
+}
+
+// GetAggregateStepped buckets deviceID's raw samples in [start, end]
+// into fixed-width windows of step and computes agg for each bucket, so
+// a client can ask for e.g. hourly averages over a day in one call
+// instead of fetching raw samples and aggregating client-side.
+func (server *TemperatureApi) GetAggregateStepped(deviceID string, start, end time.Time, step time.Duration, agg AggregateSpec) ([]AggregateBucket, error) {
+	if step <= 0 {
+		return nil, errors.New("step must be positive")
+	}
+
+	points, err := server.rawRange(deviceID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []AggregateBucket
+	bucketStart := start
+	idx := 0
+	for bucketStart.Before(end) {
+		bucketEnd := bucketStart.Add(step)
+
+		bucketPointsStart := idx
+		// The last bucket also takes a sample at exactly end, since
+		// points only holds samples up to and including end.
+		lastBucket := !bucketEnd.Before(end)
+		for idx < len(points) && (lastBucket || points[idx].Timestamp.Before(bucketEnd)) {
+			idx++
+		}
+
+		buckets = append(buckets, AggregateBucket{
+			Start:  bucketStart,
+			Result: computeAggregate(points[bucketPointsStart:idx], agg),
+		})
+
+		bucketStart = bucketEnd
+	}
+
+	return buckets, nil
+}
+
+// rawRange merges deviceID's hot tail (if any) and returns the raw
+// points within [start, end], reusing the same validation and binary
+// search GetTemperature uses.
+func (server *TemperatureApi) rawRange(deviceID string, start, end time.Time) ([]DataPoint, error) {
+	if deviceID == "" {
+		return nil, errors.New("device ID cannot be empty")
+	}
+	if end.Before(start) {
+		return nil, errors.New("end time cannot be before start time")
+	}
+
+	if _, ok := server.Data[deviceID]; !ok {
+		return nil, errors.New("no data for this device ID")
+	}
+
+	server.mergeDeviceTail(deviceID)
+
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
+	data := server.Data[deviceID]
+	if len(data.DataPoints) == 0 {
+		return nil, nil
+	}
+
+	startIndex := sort.Search(len(data.DataPoints), func(i int) bool {
+		return !data.DataPoints[i].Timestamp.Before(start)
+	})
+	endIndex := sort.Search(len(data.DataPoints), func(i int) bool {
+		return data.DataPoints[i].Timestamp.After(end)
+	})
+
+	return data.DataPoints[startIndex:endIndex], nil
+}
+
+// computeAggregate reduces points to a single AggregateResult in one
+// pass, using Welford's algorithm for mean/stddev and a P² estimator
+// for percentiles.
+func computeAggregate(points []DataPoint, agg AggregateSpec) AggregateResult {
+	if len(points) == 0 {
+		return AggregateResult{}
+	}
+
+	if agg.Kind == AggregateRate {
+		elapsed := points[len(points)-1].Timestamp.Sub(points[0].Timestamp).Seconds()
+		if elapsed == 0 {
+			return AggregateResult{Count: len(points)}
+		}
+		delta := points[len(points)-1].Temperature - points[0].Temperature
+		return AggregateResult{Value: delta / elapsed, Count: len(points)}
+	}
+
+	var digest *p2Quantile
+	switch agg.Kind {
+	case AggregateP50:
+		digest = newP2Quantile(0.50)
+	case AggregateP90:
+		digest = newP2Quantile(0.90)
+	case AggregateP99:
+		digest = newP2Quantile(0.99)
+	}
+
+	var (
+		min   = points[0].Temperature
+		max   = points[0].Temperature
+		mean  float64
+		m2    float64 // sum of squared deviations from the mean, for Welford's variance
+		count int
+	)
+
+	for _, point := range points {
+		count++
+		v := point.Temperature
+
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+
+		delta := v - mean
+		mean += delta / float64(count)
+		m2 += delta * (v - mean)
+
+		if digest != nil {
+			digest.Add(v)
+		}
+	}
+
+	result := AggregateResult{Count: count}
+	switch agg.Kind {
+	case AggregateMin:
+		result.Value = min
+	case AggregateMax:
+		result.Value = max
+	case AggregateMean:
+		result.Value = mean
+	case AggregateSum:
+		result.Value = mean * float64(count)
+	case AggregateCount:
+		result.Value = float64(count)
+	case AggregateStddev:
+		if count > 1 {
+			result.Value = math.Sqrt(m2 / float64(count))
+		}
+	case AggregateP50, AggregateP90, AggregateP99:
+		result.Value = digest.Quantile()
+	}
+
+	return result
+}
@@ -0,0 +1,138 @@
+package temperature_api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAggregate_BasicStats(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	deviceID := "device1"
+	start := time.Now()
+	temps := []float64{10, 20, 30, 40, 50}
+	for i, temp := range temps {
+		require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", start.Add(time.Duration(i)*time.Second), temp))
+	}
+	end := start.Add(time.Duration(len(temps)-1) * time.Second)
+
+	// Act & Assert
+	minResult, err := target.GetAggregate(deviceID, start, end, AggregateSpec{Kind: AggregateMin})
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, minResult.Value)
+	assert.Equal(t, 5, minResult.Count)
+
+	maxResult, err := target.GetAggregate(deviceID, start, end, AggregateSpec{Kind: AggregateMax})
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, maxResult.Value)
+
+	meanResult, err := target.GetAggregate(deviceID, start, end, AggregateSpec{Kind: AggregateMean})
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, meanResult.Value)
+
+	sumResult, err := target.GetAggregate(deviceID, start, end, AggregateSpec{Kind: AggregateSum})
+	require.NoError(t, err)
+	assert.InDelta(t, 150.0, sumResult.Value, 0.0001)
+
+	countResult, err := target.GetAggregate(deviceID, start, end, AggregateSpec{Kind: AggregateCount})
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, countResult.Value)
+}
+
+func TestGetAggregate_Percentiles(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	deviceID := "device1"
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", start.Add(time.Duration(i)*time.Second), float64(i)))
+	}
+	end := start.Add(999 * time.Second)
+
+	// Act
+	p50, err := target.GetAggregate(deviceID, start, end, AggregateSpec{Kind: AggregateP50})
+	require.NoError(t, err)
+	p90, err := target.GetAggregate(deviceID, start, end, AggregateSpec{Kind: AggregateP90})
+	require.NoError(t, err)
+	p99, err := target.GetAggregate(deviceID, start, end, AggregateSpec{Kind: AggregateP99})
+	require.NoError(t, err)
+
+	// Assert - values 0..999, so true p50/p90/p99 are ~499.5/899/989;
+	// P² is an approximation
+	assert.InDelta(t, 499.5, p50.Value, 25)
+	assert.Equal(t, 1000, p50.Count)
+	assert.InDelta(t, 899, p90.Value, 30)
+	assert.InDelta(t, 989, p99.Value, 30)
+}
+
+func TestGetAggregate_Rate(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	deviceID := "device1"
+	start := time.Now()
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", start, 10))
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", start.Add(10*time.Second), 20))
+
+	// Act
+	result, err := target.GetAggregate(deviceID, start, start.Add(10*time.Second), AggregateSpec{Kind: AggregateRate})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, result.Value) // 10 degrees over 10 seconds
+}
+
+func TestGetAggregate_NoDataInRange(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	deviceID := "device1"
+	start := time.Now()
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", start, 10))
+
+	// Act
+	result, err := target.GetAggregate(deviceID, start.Add(time.Hour), start.Add(2*time.Hour), AggregateSpec{Kind: AggregateMean})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Count)
+}
+
+func TestGetAggregateStepped_HourlyBuckets(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	deviceID := "device1"
+	start := time.Now().Truncate(time.Hour)
+
+	// two points in the first hour, one in the second
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", start, 10))
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", start.Add(30*time.Minute), 20))
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", start.Add(90*time.Minute), 40))
+
+	// Act
+	buckets, err := target.GetAggregateStepped(deviceID, start, start.Add(2*time.Hour), time.Hour, AggregateSpec{Kind: AggregateMean})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, 15.0, buckets[0].Result.Value)
+	assert.Equal(t, 2, buckets[0].Result.Count)
+	assert.Equal(t, 40.0, buckets[1].Result.Value)
+	assert.Equal(t, 1, buckets[1].Result.Count)
+}
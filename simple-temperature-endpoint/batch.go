@@ -0,0 +1,131 @@
+package temperature_api
+
+import (
+	"errors"
+	"time"
+)
+
+// PostItem is a single sample to ingest via PostTemperatureBatch.
+type PostItem struct {
+	SensorID    string
+	DeviceType  string
+	SampleTime  time.Time
+	Temperature float64
+}
+
+// PostResult is the outcome of ingesting one PostItem. Err is nil if the
+// sample was accepted.
+type PostResult struct {
+	Err error
+}
+
+// IngestOptions controls validation applied by PostTemperatureBatch.
+type IngestOptions struct {
+	// AllowOutOfOrder, when false (the default), rejects samples whose
+	// timestamp is older than the device's newest accepted point - either
+	// already stored before the batch, or earlier in the same batch -
+	// rather than silently re-sorting them in, matching TSDB's
+	// out-of-order rejection semantics.
+	AllowOutOfOrder bool
+}
+
+// PostTemperatureBatch ingests many samples across many devices under a
+// single mutex.Lock(), unlike calling PostTemperature in a loop which
+// takes one lock acquisition per sample. Items are grouped by SensorID
+// and fed through that device's deviceStore together, so each affected
+// device's tail is merged once per deviceStoreTailThreshold out-of-order
+// samples plus once at the end of the batch, instead of being re-sorted
+// per sample.
+//
+// It returns one PostResult per item, in the same order as items, so a
+// caller can tell which samples in a mixed batch were rejected. The
+// returned error is non-nil only for failures that aren't specific to
+// any one item.
+func (server *TemperatureApi) PostTemperatureBatch(items []PostItem, opts IngestOptions) ([]PostResult, error) {
+	results := make([]PostResult, len(items))
+
+	type group struct {
+		deviceType string
+		indices    []int
+	}
+	groups := make(map[string]*group)
+	order := make([]string, 0)
+
+	for i, item := range items {
+		if item.SensorID == "" {
+			results[i].Err = errors.New("device ID cannot be empty")
+			continue
+		}
+		if item.DeviceType == "" {
+			results[i].Err = errors.New("device type cannot be empty")
+			continue
+		}
+
+		g, ok := groups[item.SensorID]
+		if !ok {
+			g = &group{deviceType: item.DeviceType}
+			groups[item.SensorID] = g
+			order = append(order, item.SensorID)
+		}
+		g.deviceType = item.DeviceType
+		g.indices = append(g.indices, i)
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for _, sensorID := range order {
+		g := groups[sensorID]
+		deviceData := server.Data[sensorID]
+		store := deviceData.store()
+
+		newestExisting := store.newestTimestamp()
+		deviceData.DeviceType = g.deviceType
+
+		for _, i := range g.indices {
+			item := items[i]
+			if !opts.AllowOutOfOrder && !newestExisting.IsZero() && item.SampleTime.Before(newestExisting) {
+				results[i].Err = errors.New("sample timestamp is older than the device's newest existing point")
+				continue
+			}
+			store.Append(DataPoint{Timestamp: item.SampleTime, Temperature: item.Temperature})
+			if item.SampleTime.After(newestExisting) {
+				newestExisting = item.SampleTime
+			}
+
+			if server.wal != nil {
+				if err := server.wal.append(sensorID, g.deviceType, item.SampleTime, item.Temperature); err != nil {
+					results[i].Err = err
+				}
+			}
+		}
+
+		// A batch already pays for one lock acquisition per batch, so
+		// merge any remaining tail here rather than leaving it pending -
+		// callers reading the batch's result back via
+		// server.Data shouldn't have to know about deviceStore internals.
+		store.Points()
+		store.Trim(MaxDataPoints)
+		deviceData.storeInto(store)
+		server.Data[sensorID] = deviceData
+	}
+
+	return results, nil
+}
+
+// newestTimestamp returns the timestamp of the most recent point held
+// in either segment, or the zero time if there are none. Once a tail is
+// pending, deviceStore.Append sends every point there, newer ones
+// included, so the unsorted tail is scanned as well as cold's last entry.
+func (s deviceStore) newestTimestamp() time.Time {
+	var newest time.Time
+	if n := len(s.cold); n > 0 {
+		newest = s.cold[n-1].Timestamp
+	}
+	for _, point := range s.tail {
+		if point.Timestamp.After(newest) {
+			newest = point.Timestamp
+		}
+	}
+	return newest
+}
@@ -0,0 +1,130 @@
+package temperature_api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostTemperatureBatch_GroupsBySensorAndSortsOnce(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	now := time.Now()
+	items := []PostItem{
+		{SensorID: "device1", DeviceType: "temperature_sensor", SampleTime: now.Add(2 * time.Second), Temperature: 22.0},
+		{SensorID: "device2", DeviceType: "temperature_sensor", SampleTime: now, Temperature: 18.0},
+		{SensorID: "device1", DeviceType: "temperature_sensor", SampleTime: now, Temperature: 20.0},
+	}
+
+	// Act - device1's items arrive out of order within the batch itself,
+	// so this needs AllowOutOfOrder to land both
+	results, err := target.PostTemperatureBatch(items, IngestOptions{AllowOutOfOrder: true})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, result := range results {
+		assert.NoError(t, result.Err)
+	}
+
+	device1 := target.Data["device1"]
+	require.Len(t, device1.DataPoints, 2)
+	assert.Equal(t, 20.0, device1.DataPoints[0].Temperature)
+	assert.Equal(t, 22.0, device1.DataPoints[1].Temperature)
+
+	device2 := target.Data["device2"]
+	require.Len(t, device2.DataPoints, 1)
+}
+
+func TestPostTemperatureBatch_RejectsIntraBatchOutOfOrderByDefault(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	now := time.Now()
+
+	// Act - a brand-new device, but the two items in the batch arrive out
+	// of order relative to each other
+	results, err := target.PostTemperatureBatch([]PostItem{
+		{SensorID: "device1", DeviceType: "temperature_sensor", SampleTime: now.Add(2 * time.Second), Temperature: 22.0},
+		{SensorID: "device1", DeviceType: "temperature_sensor", SampleTime: now, Temperature: 20.0},
+	}, IngestOptions{})
+
+	// Assert - the first item is accepted and becomes the device's newest
+	// point, so the second, older one is rejected even though nothing
+	// existed for this device before the batch
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.Error(t, results[1].Err)
+	assert.Len(t, target.Data["device1"].DataPoints, 1)
+}
+
+func TestPostTemperatureBatch_RejectsOutOfOrderByDefault(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	now := time.Now()
+	require.NoError(t, target.PostTemperature("device1", "temperature_sensor", now, 20.0))
+
+	// Act - a second batch with a sample older than the existing newest point
+	results, err := target.PostTemperatureBatch([]PostItem{
+		{SensorID: "device1", DeviceType: "temperature_sensor", SampleTime: now.Add(-time.Minute), Temperature: 19.0},
+	}, IngestOptions{AllowOutOfOrder: false})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Error(t, results[0].Err)
+	assert.Len(t, target.Data["device1"].DataPoints, 1)
+}
+
+func TestPostTemperatureBatch_AllowsOutOfOrderWhenConfigured(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	now := time.Now()
+	require.NoError(t, target.PostTemperature("device1", "temperature_sensor", now, 20.0))
+
+	// Act
+	results, err := target.PostTemperatureBatch([]PostItem{
+		{SensorID: "device1", DeviceType: "temperature_sensor", SampleTime: now.Add(-time.Minute), Temperature: 19.0},
+	}, IngestOptions{AllowOutOfOrder: true})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+	assert.Len(t, target.Data["device1"].DataPoints, 2)
+}
+
+func TestPostTemperatureBatch_PerItemValidation(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	// Act
+	results, err := target.PostTemperatureBatch([]PostItem{
+		{SensorID: "", DeviceType: "temperature_sensor", SampleTime: time.Now(), Temperature: 20.0},
+		{SensorID: "device1", DeviceType: "", SampleTime: time.Now(), Temperature: 20.0},
+		{SensorID: "device1", DeviceType: "temperature_sensor", SampleTime: time.Now(), Temperature: 20.0},
+	}, IngestOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Error(t, results[0].Err)
+	assert.Error(t, results[1].Err)
+	assert.NoError(t, results[2].Err)
+}
@@ -0,0 +1,101 @@
+package temperature_api
+
+import "sort"
+
+// deviceStoreTailThreshold is how many out-of-order points accumulate in
+// a device's hot tail before it's merged into the cold segment.
+const deviceStoreTailThreshold = 128
+
+// deviceStore is the append-only "hot" tail plus sorted "cold" head
+// storage for one device's raw samples, analogous to an LSM memtable
+// sitting in front of a sorted run. In-order (monotonically increasing)
+// points land directly in cold in O(1) while no tail is pending; anything
+// else lands in tail and is merge-sorted into cold once tail reaches
+// deviceStoreTailThreshold, or on demand via Points.
+type deviceStore struct {
+	cold []DataPoint
+	tail []DataPoint
+}
+
+// Append adds a point to the store. When there's no pending tail, a
+// point whose timestamp is not before the newest point already in cold
+// is appended directly to cold in O(1) - the common case for
+// monotonically-arriving sensor data. Anything else (an out-of-order
+// point, or any point while a tail is already pending) goes to the
+// unsorted tail instead.
+func (s *deviceStore) Append(point DataPoint) {
+	if n := len(s.cold); len(s.tail) == 0 && (n == 0 || !point.Timestamp.Before(s.cold[n-1].Timestamp)) {
+		s.cold = append(s.cold, point)
+		return
+	}
+
+	s.tail = append(s.tail, point)
+	if len(s.tail) >= deviceStoreTailThreshold {
+		s.mergeTail()
+	}
+}
+
+// Points returns all points in timestamp order, merging the tail into
+// cold first if the tail is non-empty.
+func (s *deviceStore) Points() []DataPoint {
+	s.mergeTail()
+	return s.cold
+}
+
+// Len returns the total number of points held, across both segments.
+func (s *deviceStore) Len() int {
+	return len(s.cold) + len(s.tail)
+}
+
+// Trim evicts the oldest points so that no more than max points remain
+// in total. The globally oldest point isn't necessarily in cold - an
+// out-of-order arrival sitting in tail can be older than everything in
+// cold - so whenever eviction is actually needed, the tail is merged
+// first; only once the store is a single sorted run can eviction safely
+// trim cold's front. When the store is within max, nothing is merged or
+// evicted, so a device that occasionally receives an out-of-order point
+// still gets to batch up to deviceStoreTailThreshold of them before
+// paying for a merge.
+func (s *deviceStore) Trim(max int) {
+	if s.Len()-max <= 0 {
+		return
+	}
+
+	s.mergeTail()
+	if excess := len(s.cold) - max; excess > 0 {
+		s.cold = s.cold[excess:]
+	}
+}
+
+func (s *deviceStore) mergeTail() {
+	if len(s.tail) == 0 {
+		return
+	}
+
+	sort.Slice(s.tail, func(i, j int) bool {
+		return s.tail[i].Timestamp.Before(s.tail[j].Timestamp)
+	})
+	s.cold = mergeSortedPoints(s.cold, s.tail)
+	s.tail = nil
+}
+
+// mergeSortedPoints merges two already-sorted slices into one sorted
+// slice, like the merge step of a merge sort.
+func mergeSortedPoints(a, b []DataPoint) []DataPoint {
+	merged := make([]DataPoint, 0, len(a)+len(b))
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i].Timestamp.Before(b[j].Timestamp) {
+			merged = append(merged, a[i])
+			i++
+		} else {
+			merged = append(merged, b[j])
+			j++
+		}
+	}
+	merged = append(merged, a[i:]...)
+	merged = append(merged, b[j:]...)
+
+	return merged
+}
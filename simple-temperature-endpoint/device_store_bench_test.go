@@ -0,0 +1,56 @@
+package temperature_api
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+// legacySortOnInsert mimics the original PostTemperature behavior this
+// package used before deviceStore: a full sort.Slice on every insert.
+// It's kept here only so BenchmarkPostTemperature_LegacySortOnInsert can
+// show the difference against the current O(1)-amortized
+// deviceStore.Append in BenchmarkPostTemperature_DeviceStoreAppend.
+func legacySortOnInsert(points []DataPoint, point DataPoint) []DataPoint {
+	points = append(points, point)
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(points[j].Timestamp)
+	})
+	return points
+}
+
+// BenchmarkPostTemperature_LegacySortOnInsert is O(n log n) per insert,
+// so inserting n points costs O(n^2 log n) overall.
+func BenchmarkPostTemperature_LegacySortOnInsert(b *testing.B) {
+	const inserts = 100_000
+	start := time.Unix(0, 0)
+
+	for i := 0; i < b.N; i++ {
+		var points []DataPoint
+		for j := 0; j < inserts; j++ {
+			points = legacySortOnInsert(points, DataPoint{
+				Timestamp:   start.Add(time.Duration(j) * time.Second),
+				Temperature: 20.0,
+			})
+		}
+	}
+}
+
+// BenchmarkPostTemperature_DeviceStoreAppend inserts the same number of
+// monotonically increasing points through deviceStore.Append, which is
+// O(1) amortized per insert (no tail merge ever triggers, since every
+// point is in order) for a total of O(n).
+func BenchmarkPostTemperature_DeviceStoreAppend(b *testing.B) {
+	const inserts = 100_000
+	start := time.Unix(0, 0)
+
+	for i := 0; i < b.N; i++ {
+		var store deviceStore
+		for j := 0; j < inserts; j++ {
+			store.Append(DataPoint{
+				Timestamp:   start.Add(time.Duration(j) * time.Second),
+				Temperature: 20.0,
+			})
+		}
+	}
+}
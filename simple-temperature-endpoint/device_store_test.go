@@ -0,0 +1,111 @@
+package temperature_api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceStore_InOrderAppendsStayInCold(t *testing.T) {
+	// Arrange
+	var store deviceStore
+	start := time.Now()
+
+	// Act - append strictly increasing timestamps
+	for i := 0; i < 10; i++ {
+		store.Append(DataPoint{Timestamp: start.Add(time.Duration(i) * time.Second), Temperature: float64(i)})
+	}
+
+	// Assert - all 10 landed directly in cold, no tail pending
+	assert.Len(t, store.cold, 10)
+	assert.Empty(t, store.tail)
+}
+
+func TestDeviceStore_OutOfOrderMergesOnRead(t *testing.T) {
+	// Arrange
+	var store deviceStore
+	start := time.Now()
+	store.Append(DataPoint{Timestamp: start.Add(2 * time.Second), Temperature: 2})
+	store.Append(DataPoint{Timestamp: start, Temperature: 0}) // out of order, goes to tail
+
+	// Act
+	points := store.Points()
+
+	// Assert
+	assert.Empty(t, store.tail)
+	assert.Equal(t, []DataPoint{
+		{Timestamp: start, Temperature: 0},
+		{Timestamp: start.Add(2 * time.Second), Temperature: 2},
+	}, points)
+}
+
+func TestDeviceStore_MergesAutomaticallyAtThreshold(t *testing.T) {
+	// Arrange
+	var store deviceStore
+	start := time.Now()
+	store.Append(DataPoint{Timestamp: start.Add(time.Hour), Temperature: 0})
+
+	// Act - push deviceStoreTailThreshold out-of-order points into the tail
+	for i := 0; i < deviceStoreTailThreshold; i++ {
+		store.Append(DataPoint{Timestamp: start.Add(time.Duration(i) * time.Second), Temperature: float64(i)})
+	}
+
+	// Assert - the tail merged itself once it hit the threshold
+	assert.Empty(t, store.tail)
+	assert.Len(t, store.cold, deviceStoreTailThreshold+1)
+}
+
+func TestDeviceStore_TrimEvictsOldestAcrossPendingTail(t *testing.T) {
+	// Arrange - cold holds t10, t20, t30; an out-of-order arrival older
+	// than all of them sits unmerged in tail
+	var store deviceStore
+	start := time.Now()
+	store.Append(DataPoint{Timestamp: start.Add(10 * time.Second), Temperature: 10})
+	store.Append(DataPoint{Timestamp: start.Add(20 * time.Second), Temperature: 20})
+	store.Append(DataPoint{Timestamp: start.Add(30 * time.Second), Temperature: 30})
+	store.Append(DataPoint{Timestamp: start.Add(1 * time.Second), Temperature: 1}) // out of order, goes to tail
+
+	// Act - trim down to 3, which should evict the globally oldest point
+	// (the one in tail), not t10 from cold
+	store.Trim(3)
+
+	// Assert
+	points := store.Points()
+	assert.Len(t, points, 3)
+	assert.Equal(t, []float64{10, 20, 30}, []float64{points[0].Temperature, points[1].Temperature, points[2].Temperature})
+}
+
+func TestDeviceStore_TrimLeavesTailPendingWhenWithinCapacity(t *testing.T) {
+	// Arrange - one out-of-order point sits in tail, but the store is
+	// nowhere near max
+	var store deviceStore
+	start := time.Now()
+	store.Append(DataPoint{Timestamp: start.Add(10 * time.Second), Temperature: 10})
+	store.Append(DataPoint{Timestamp: start, Temperature: 0}) // out of order, goes to tail
+
+	// Act
+	store.Trim(100)
+
+	// Assert - Trim is a no-op when the store is within max, so the tail
+	// is left pending rather than merged
+	assert.Len(t, store.tail, 1)
+	assert.Len(t, store.cold, 1)
+}
+
+func TestDeviceStore_TrimEvictsOldest(t *testing.T) {
+	// Arrange
+	var store deviceStore
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		store.Append(DataPoint{Timestamp: start.Add(time.Duration(i) * time.Second), Temperature: float64(i)})
+	}
+
+	// Act
+	store.Trim(3)
+
+	// Assert
+	points := store.Points()
+	assert.Len(t, points, 3)
+	assert.Equal(t, 2.0, points[0].Temperature)
+}
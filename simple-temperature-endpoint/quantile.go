@@ -0,0 +1,132 @@
+package temperature_api
+
+import "sort"
+
+// p2Quantile estimates a single quantile of a stream of float64 values
+// in one pass and constant memory, using the P² (piecewise-parabolic)
+// algorithm (Jain & Chlamtac, 1985). This avoids materializing and
+// sorting the whole range just to read off a percentile.
+type p2Quantile struct {
+	p float64
+
+	count      int
+	initial    []float64 // buffers the first 5 samples before the markers are seeded
+	heights    [5]float64
+	positions  [5]int
+	desired    [5]float64
+	increments [5]float64
+}
+
+func newP2Quantile(p float64) *p2Quantile {
+	return &p2Quantile{p: p}
+}
+
+// Add feeds one more sample into the estimator.
+func (q *p2Quantile) Add(v float64) {
+	q.count++
+
+	if len(q.initial) < 5 {
+		q.initial = append(q.initial, v)
+		if len(q.initial) == 5 {
+			q.seed()
+		}
+		return
+	}
+
+	q.addMarker(v)
+}
+
+// Quantile returns the current estimate of the configured quantile. If
+// fewer than 5 samples have been seen, it falls back to an exact
+// computation over that small buffer.
+func (q *p2Quantile) Quantile() float64 {
+	if len(q.initial) < 5 {
+		if len(q.initial) == 0 {
+			return 0
+		}
+		sorted := append([]float64(nil), q.initial...)
+		sort.Float64s(sorted)
+		idx := int(q.p * float64(len(sorted)-1))
+		return sorted[idx]
+	}
+
+	return q.heights[2]
+}
+
+// seed initializes the 5 markers from the first 5 samples, sorted.
+func (q *p2Quantile) seed() {
+	sorted := append([]float64(nil), q.initial...)
+	sort.Float64s(sorted)
+
+	for i := 0; i < 5; i++ {
+		q.heights[i] = sorted[i]
+		q.positions[i] = i + 1
+	}
+
+	q.desired = [5]float64{1, 1 + 2*q.p, 1 + 4*q.p, 3 + 2*q.p, 5}
+	q.increments = [5]float64{0, q.p / 2, q.p, (1 + q.p) / 2, 1}
+}
+
+// addMarker implements one step of the P² algorithm for a new sample:
+// find which marker cell v falls into, bump positions of markers above
+// it, advance the desired positions, then adjust the interior markers'
+// heights using piecewise-parabolic (falling back to linear)
+// interpolation if they've drifted too far from their desired position.
+func (q *p2Quantile) addMarker(v float64) {
+	// Find the cell k (0..3) such that heights[k] <= v < heights[k+1],
+	// using the heights as they stood *before* this sample - updating
+	// heights[0]/heights[4] first would make v compare against itself
+	// and the loop would never advance past k=3.
+	var k int
+	switch {
+	case v < q.heights[0]:
+		q.heights[0] = v
+		k = 0
+	case v >= q.heights[4]:
+		q.heights[4] = v
+		k = 3
+	default:
+		k = 0
+		for k < 3 && v >= q.heights[k+1] {
+			k++
+		}
+	}
+
+	for i := k + 1; i < 5; i++ {
+		q.positions[i]++
+	}
+	for i := 0; i < 5; i++ {
+		q.desired[i] += q.increments[i]
+	}
+
+	for i := 1; i < 4; i++ {
+		d := q.desired[i] - float64(q.positions[i])
+		if (d >= 1 && q.positions[i+1]-q.positions[i] > 1) ||
+			(d <= -1 && q.positions[i-1]-q.positions[i] < -1) {
+			sign := 1
+			if d < 0 {
+				sign = -1
+			}
+
+			newHeight := q.parabolic(i, sign)
+			if q.heights[i-1] < newHeight && newHeight < q.heights[i+1] {
+				q.heights[i] = newHeight
+			} else {
+				q.heights[i] = q.linear(i, sign)
+			}
+			q.positions[i] += sign
+		}
+	}
+}
+
+func (q *p2Quantile) parabolic(i, sign int) float64 {
+	d := float64(sign)
+	return q.heights[i] + d/float64(q.positions[i+1]-q.positions[i-1])*
+		((float64(q.positions[i]-q.positions[i-1])+d)*(q.heights[i+1]-q.heights[i])/float64(q.positions[i+1]-q.positions[i])+
+			(float64(q.positions[i+1]-q.positions[i])-d)*(q.heights[i]-q.heights[i-1])/float64(q.positions[i]-q.positions[i-1]))
+}
+
+func (q *p2Quantile) linear(i, sign int) float64 {
+	j := i + sign
+	return q.heights[i] + float64(sign)*(q.heights[j]-q.heights[i])/float64(q.positions[j]-q.positions[i])
+}
@@ -0,0 +1,61 @@
+package temperature_api
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestP2Quantile_ApproximatesMedianOfUniformData(t *testing.T) {
+	// Arrange
+	q := newP2Quantile(0.5)
+
+	// Act - feed in 0..999 in order
+	for i := 0; i < 1000; i++ {
+		q.Add(float64(i))
+	}
+
+	// Assert - true median is 499.5; P² is an approximation
+	assert.InDelta(t, 499.5, q.Quantile(), 25)
+}
+
+func TestP2Quantile_ApproximatesP99OfUniformData(t *testing.T) {
+	// Arrange
+	q := newP2Quantile(0.99)
+
+	// Act
+	for i := 0; i < 1000; i++ {
+		q.Add(float64(i))
+	}
+
+	// Assert - true p99 is ~989
+	assert.InDelta(t, 989, q.Quantile(), 30)
+}
+
+func TestP2Quantile_FallsBackExactlyBelowFiveSamples(t *testing.T) {
+	// Arrange
+	q := newP2Quantile(0.5)
+
+	// Act
+	q.Add(3)
+	q.Add(1)
+	q.Add(2)
+
+	// Assert - sorted [1,2,3], median index = int(0.5*2) = 1 -> value 2
+	assert.Equal(t, 2.0, q.Quantile())
+}
+
+func TestP2Quantile_HandlesConstantStream(t *testing.T) {
+	// Arrange
+	q := newP2Quantile(0.9)
+
+	// Act
+	for i := 0; i < 20; i++ {
+		q.Add(42)
+	}
+
+	// Assert
+	assert.False(t, math.IsNaN(q.Quantile()))
+	assert.Equal(t, 42.0, q.Quantile())
+}
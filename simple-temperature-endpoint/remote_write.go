@@ -0,0 +1,134 @@
+package temperature_api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// RemoteWriteConfig controls how incoming prompb.WriteRequest series are
+// mapped onto TemperatureApi devices.
+type RemoteWriteConfig struct {
+	// DeviceIDLabel is the label whose value becomes the PostTemperature
+	// sensor ID. Defaults to "sensor_id" if empty.
+	DeviceIDLabel string
+	// DeviceTypeLabel is the label whose value becomes the
+	// PostTemperature device type. Defaults to "device_type" if empty.
+	DeviceTypeLabel string
+}
+
+// RemoteWriteHandler implements http.Handler for Prometheus's
+// remote_write protocol, so existing Prometheus agents, Grafana Agent,
+// or an OpenTelemetry collector's Prometheus remote-write exporter can
+// ship samples straight into a TemperatureApi.
+type RemoteWriteHandler struct {
+	Server *TemperatureApi
+	Config RemoteWriteConfig
+}
+
+// NewRemoteWriteHandler returns a handler that ingests remote_write
+// payloads into server using cfg. A zero-value RemoteWriteConfig falls
+// back to the "sensor_id" / "device_type" label names.
+func NewRemoteWriteHandler(server *TemperatureApi, cfg RemoteWriteConfig) *RemoteWriteHandler {
+	if cfg.DeviceIDLabel == "" {
+		cfg.DeviceIDLabel = "sensor_id"
+	}
+	if cfg.DeviceTypeLabel == "" {
+		cfg.DeviceTypeLabel = "device_type"
+	}
+	return &RemoteWriteHandler{Server: server, Config: cfg}
+}
+
+func (h *RemoteWriteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading request body: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	writeReq, err := decodeWriteRequest(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("decoding remote_write payload: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	items, err := h.Config.toBatch(writeReq.Timeseries)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.Server.PostTemperatureBatch(items, IngestOptions{AllowOutOfOrder: true}); err != nil {
+		http.Error(w, fmt.Sprintf("ingesting samples: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeWriteRequest snappy-decompresses and unmarshals a remote_write
+// request body into a prompb.WriteRequest.
+func decodeWriteRequest(body []byte) (*prompb.WriteRequest, error) {
+	decompressed, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("snappy decode: %w", err)
+	}
+
+	var writeReq prompb.WriteRequest
+	if err := proto.Unmarshal(decompressed, &writeReq); err != nil {
+		return nil, fmt.Errorf("protobuf unmarshal: %w", err)
+	}
+
+	return &writeReq, nil
+}
+
+// toBatch flattens every sample across series into PostItems, ready to
+// hand to PostTemperatureBatch so the whole payload is ingested under a
+// single lock acquisition rather than one per sample.
+func (cfg RemoteWriteConfig) toBatch(series []prompb.TimeSeries) ([]PostItem, error) {
+	items := make([]PostItem, 0, len(series))
+
+	for _, ts := range series {
+		deviceID, deviceType, err := cfg.labelsToDevice(ts.Labels)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sample := range ts.Samples {
+			items = append(items, PostItem{
+				SensorID:    deviceID,
+				DeviceType:  deviceType,
+				SampleTime:  time.UnixMilli(sample.Timestamp),
+				Temperature: sample.Value,
+			})
+		}
+	}
+
+	return items, nil
+}
+
+func (cfg RemoteWriteConfig) labelsToDevice(labels []prompb.Label) (deviceID, deviceType string, err error) {
+	for _, label := range labels {
+		switch label.Name {
+		case cfg.DeviceIDLabel:
+			deviceID = label.Value
+		case cfg.DeviceTypeLabel:
+			deviceType = label.Value
+		}
+	}
+
+	if deviceID == "" {
+		return "", "", errors.New("remote_write series missing required " + cfg.DeviceIDLabel + " label")
+	}
+	if deviceType == "" {
+		deviceType = "unknown"
+	}
+
+	return deviceID, deviceType, nil
+}
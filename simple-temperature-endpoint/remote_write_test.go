@@ -0,0 +1,162 @@
+package temperature_api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeWriteRequest snappy-compresses and marshals req the same way a
+// real Prometheus remote_write client would, for tests that exercise
+// ServeHTTP's wire format end to end rather than calling toBatch directly.
+func encodeWriteRequest(t *testing.T, req *prompb.WriteRequest) []byte {
+	t.Helper()
+	marshaled, err := proto.Marshal(req)
+	require.NoError(t, err)
+	return snappy.Encode(nil, marshaled)
+}
+
+func TestRemoteWriteHandler_PostSeries(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	handler := NewRemoteWriteHandler(&target, RemoteWriteConfig{})
+	now := time.Now()
+
+	series := []prompb.TimeSeries{
+		{
+			Labels: []prompb.Label{
+				{Name: "sensor_id", Value: "device1"},
+				{Name: "device_type", Value: "temperature_sensor"},
+			},
+			Samples: []prompb.Sample{
+				{Timestamp: now.UnixMilli(), Value: 21.5},
+				{Timestamp: now.Add(time.Minute).UnixMilli(), Value: 22.0},
+			},
+		},
+	}
+
+	// Act
+	items, err := handler.Config.toBatch(series)
+	require.NoError(t, err)
+	_, err = target.PostTemperatureBatch(items, IngestOptions{AllowOutOfOrder: true})
+
+	// Assert
+	require.NoError(t, err)
+	data := target.Data["device1"]
+	assert.Equal(t, "temperature_sensor", data.DeviceType)
+	require.Len(t, data.DataPoints, 2)
+	assert.Equal(t, 21.5, data.DataPoints[0].Temperature)
+}
+
+func TestRemoteWriteHandler_MissingDeviceIDLabel(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	handler := NewRemoteWriteHandler(&target, RemoteWriteConfig{})
+
+	series := []prompb.TimeSeries{
+		{
+			Labels:  []prompb.Label{{Name: "device_type", Value: "temperature_sensor"}},
+			Samples: []prompb.Sample{{Timestamp: time.Now().UnixMilli(), Value: 21.5}},
+		},
+	}
+
+	// Act
+	_, err := handler.Config.toBatch(series)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestRemoteWriteHandler_ServeHTTP_AcceptsValidPayload(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	handler := NewRemoteWriteHandler(&target, RemoteWriteConfig{})
+	now := time.Now()
+
+	body := encodeWriteRequest(t, &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: "sensor_id", Value: "device1"},
+					{Name: "device_type", Value: "temperature_sensor"},
+				},
+				Samples: []prompb.Sample{
+					{Timestamp: now.UnixMilli(), Value: 21.5},
+					{Timestamp: now.Add(time.Minute).UnixMilli(), Value: 22.0},
+				},
+			},
+		},
+	})
+
+	// Act
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	data := target.Data["device1"]
+	assert.Equal(t, "temperature_sensor", data.DeviceType)
+	require.Len(t, data.DataPoints, 2)
+	assert.Equal(t, 21.5, data.DataPoints[0].Temperature)
+}
+
+func TestRemoteWriteHandler_ServeHTTP_RejectsInvalidSnappyPayload(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	handler := NewRemoteWriteHandler(&target, RemoteWriteConfig{})
+
+	// Act - not valid snappy-framed data at all
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader([]byte("not a valid snappy payload")))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRemoteWriteHandler_ServeHTTP_RejectsMissingDeviceIDLabel(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	defer target.Stop()
+
+	handler := NewRemoteWriteHandler(&target, RemoteWriteConfig{})
+
+	body := encodeWriteRequest(t, &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels:  []prompb.Label{{Name: "device_type", Value: "temperature_sensor"}},
+				Samples: []prompb.Sample{{Timestamp: time.Now().UnixMilli(), Value: 21.5}},
+			},
+		},
+	})
+
+	// Act - valid snappy/protobuf, but missing the required label
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -2,6 +2,7 @@ package temperature_api
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"sync"
 	"time"
@@ -12,11 +13,42 @@ const MaxDataPoints = 10000
 type TemperatureApi struct {
 	Data  map[string]DeviceData
 	mutex sync.RWMutex
+
+	retention RetentionConfig
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+
+	wal *wal
 }
 
 type DeviceData struct {
 	DeviceType string      `json:"device_type"`
 	DataPoints []DataPoint `json:"data_points"`
+
+	// tail holds raw points not yet merged into DataPoints: see
+	// deviceStore. It's always merged (DataPoints sorted, tail emptied)
+	// before DataPoints is read from outside this file, so callers never
+	// observe it directly.
+	tail []DataPoint
+
+	// OneMinute and OneHour hold downsampled rollups of DataPoints that have
+	// aged out of the raw retention window. They're maintained by the
+	// compactor goroutine started in Init, not by PostTemperature directly.
+	OneMinute []AggregatePoint `json:"one_minute_points,omitempty"`
+	OneHour   []AggregatePoint `json:"one_hour_points,omitempty"`
+}
+
+// store returns a deviceStore view over data's cold/tail segments. Any
+// mutation through the returned store must be written back to the map
+// via its cold/tail fields (see storeInto).
+func (data DeviceData) store() deviceStore {
+	return deviceStore{cold: data.DataPoints, tail: data.tail}
+}
+
+// storeInto writes s's segments back into data.
+func (data *DeviceData) storeInto(s deviceStore) {
+	data.DataPoints = s.cold
+	data.tail = s.tail
 }
 
 type ResponseBody struct {
@@ -29,8 +61,309 @@ type DataPoint struct {
 	Temperature float64   `json:"temperature"`
 }
 
+// AggregatePoint is a single bucket of a downsampled rollup tier: the
+// min/max/mean/count of all raw samples that fell within the bucket.
+type AggregatePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Mean      float64   `json:"mean"`
+	Count     int       `json:"count"`
+}
+
+// Resolution selects which storage tier GetTemperature reads from.
+type Resolution int
+
+const (
+	// ResolutionRaw returns raw, full-precision samples. This is the
+	// default when no resolution is given.
+	ResolutionRaw Resolution = iota
+	// ResolutionOneMinute returns 1-minute min/max/mean/count rollups.
+	ResolutionOneMinute
+	// ResolutionOneHour returns 1-hour min/max/mean/count rollups.
+	ResolutionOneHour
+)
+
+// RetentionConfig controls how long raw and rolled-up samples are kept,
+// and how often the background compactor runs to enforce it. It mirrors
+// Prometheus's per-tier retention: raw samples age into 1-minute rollups,
+// which in turn age into 1-hour rollups, which are eventually dropped.
+type RetentionConfig struct {
+	// RawRetention is how long full-precision samples are kept before
+	// being folded into the 1-minute tier.
+	RawRetention time.Duration
+	// OneMinuteRetention is how long 1-minute rollups are kept before
+	// being folded into the 1-hour tier.
+	OneMinuteRetention time.Duration
+	// OneHourRetention is how long 1-hour rollups are kept before being
+	// dropped entirely.
+	OneHourRetention time.Duration
+	// CompactInterval is how often the compactor goroutine wakes up to
+	// apply retention and fold aged-out points into the next tier.
+	CompactInterval time.Duration
+}
+
+// DefaultRetentionConfig returns the retention policy used by Init. It
+// keeps a day of raw samples, a week of 1-minute rollups, and a year of
+// 1-hour rollups, compacting every minute.
+func DefaultRetentionConfig() RetentionConfig {
+	return RetentionConfig{
+		RawRetention:       24 * time.Hour,
+		OneMinuteRetention: 7 * 24 * time.Hour,
+		OneHourRetention:   365 * 24 * time.Hour,
+		CompactInterval:    time.Minute,
+	}
+}
+
 func (server *TemperatureApi) Init() {
+	server.InitWithRetention(DefaultRetentionConfig())
+}
+
+// InitWithRetention initializes the server like Init, but with a
+// caller-supplied retention policy instead of DefaultRetentionConfig.
+func (server *TemperatureApi) InitWithRetention(cfg RetentionConfig) {
 	server.Data = make(map[string]DeviceData)
+	server.retention = cfg
+	server.stopCh = make(chan struct{})
+	server.stopOnce = sync.Once{}
+
+	if cfg.CompactInterval > 0 {
+		go server.runCompactor(cfg.CompactInterval)
+	}
+}
+
+// InitWithWAL initializes the server like InitWithRetention, but backs
+// it with a write-ahead log rooted at dir so that data survives a
+// restart. Any existing segments under dir are replayed to rebuild
+// server.Data before InitWithWAL returns.
+func (server *TemperatureApi) InitWithWAL(dir string, cfg RetentionConfig) error {
+	server.InitWithRetention(cfg)
+
+	w, data, err := openWAL(dir)
+	if err != nil {
+		return fmt.Errorf("opening WAL at %s: %w", dir, err)
+	}
+
+	server.wal = w
+	server.Data = data
+
+	return nil
+}
+
+// Stop shuts down the background compactor goroutine started by Init,
+// and closes the write-ahead log if one was opened by InitWithWAL.
+func (server *TemperatureApi) Stop() {
+	if server.stopCh != nil {
+		server.stopOnce.Do(func() {
+			close(server.stopCh)
+		})
+	}
+	if server.wal != nil {
+		server.wal.close()
+	}
+}
+
+func (server *TemperatureApi) runCompactor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			server.compact(time.Now())
+		case <-server.stopCh:
+			return
+		}
+	}
+}
+
+// mergeDeviceTail merge-sorts deviceID's hot tail into its cold segment
+// if it has one, so GetTemperature can binary search a fully sorted
+// slice. It takes the write lock only when there's actually a tail to
+// merge, so the common case (no pending out-of-order points) stays on
+// the read-lock fast path.
+func (server *TemperatureApi) mergeDeviceTail(deviceID string) {
+	server.mutex.RLock()
+	hasTail := len(server.Data[deviceID].tail) > 0
+	server.mutex.RUnlock()
+
+	if !hasTail {
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	deviceData := server.Data[deviceID]
+	store := deviceData.store()
+	store.Points() // merges tail into cold
+	deviceData.storeInto(store)
+	server.Data[deviceID] = deviceData
+}
+
+// compact applies the retention policy relative to now: raw points older
+// than RawRetention are folded into the 1-minute tier, 1-minute rollups
+// older than OneMinuteRetention are folded into the 1-hour tier, and
+// 1-hour rollups older than OneHourRetention are dropped.
+func (server *TemperatureApi) compact(now time.Time) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	rawCutoff := now.Add(-server.retention.RawRetention)
+	oneMinuteCutoff := now.Add(-server.retention.OneMinuteRetention)
+	oneHourCutoff := now.Add(-server.retention.OneHourRetention)
+
+	for deviceID, data := range server.Data {
+		store := data.store()
+		data.storeInto(deviceStore{cold: store.Points()})
+
+		agedOut, remaining := splitBefore(data.DataPoints, rawCutoff)
+		if len(agedOut) > 0 {
+			data.DataPoints = remaining
+			data.OneMinute = mergeAggregates(data.OneMinute, rollup(agedOut, time.Minute))
+		}
+
+		agedMinutes, remainingMinutes := splitAggregatesBefore(data.OneMinute, oneMinuteCutoff)
+		if len(agedMinutes) > 0 {
+			data.OneMinute = remainingMinutes
+			data.OneHour = mergeAggregates(data.OneHour, rollupAggregates(agedMinutes, time.Hour))
+		}
+
+		_, remainingHours := splitAggregatesBefore(data.OneHour, oneHourCutoff)
+		data.OneHour = remainingHours
+
+		server.Data[deviceID] = data
+	}
+}
+
+// splitBefore partitions sorted data points into those strictly before
+// cutoff ("aged out") and those at or after it ("remaining").
+func splitBefore(points []DataPoint, cutoff time.Time) (agedOut, remaining []DataPoint) {
+	idx := sort.Search(len(points), func(i int) bool {
+		return !points[i].Timestamp.Before(cutoff)
+	})
+	return points[:idx], points[idx:]
+}
+
+func splitAggregatesBefore(points []AggregatePoint, cutoff time.Time) (agedOut, remaining []AggregatePoint) {
+	idx := sort.Search(len(points), func(i int) bool {
+		return !points[i].Timestamp.Before(cutoff)
+	})
+	return points[:idx], points[idx:]
+}
+
+// rollup folds raw data points into fixed-width buckets of the given
+// width, producing one AggregatePoint per bucket.
+func rollup(points []DataPoint, bucketWidth time.Duration) []AggregatePoint {
+	buckets := make([]AggregatePoint, 0, len(points))
+
+	for _, point := range points {
+		bucketStart := point.Timestamp.Truncate(bucketWidth)
+		if n := len(buckets); n > 0 && buckets[n-1].Timestamp.Equal(bucketStart) {
+			buckets[n-1].addSample(point.Temperature)
+			continue
+		}
+		bucket := AggregatePoint{Timestamp: bucketStart}
+		bucket.addSample(point.Temperature)
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets
+}
+
+// rollupAggregates folds already-aggregated points into coarser buckets,
+// weighting by each input bucket's Count.
+func rollupAggregates(points []AggregatePoint, bucketWidth time.Duration) []AggregatePoint {
+	buckets := make([]AggregatePoint, 0, len(points))
+
+	for _, point := range points {
+		bucketStart := point.Timestamp.Truncate(bucketWidth)
+		if n := len(buckets); n > 0 && buckets[n-1].Timestamp.Equal(bucketStart) {
+			buckets[n-1].merge(point)
+			continue
+		}
+		bucket := point
+		bucket.Timestamp = bucketStart
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets
+}
+
+// mergeAggregates merges newPoints into existing. Both are individually
+// sorted by Timestamp, but not necessarily relative to each other - a
+// late out-of-order raw sample can cause a later compact() call to
+// produce buckets older than ones an earlier call already folded in, or
+// landing on a bucket that already exists - so this does a sorted merge
+// rather than a naive append, combining buckets that share a Timestamp.
+func mergeAggregates(existing, newPoints []AggregatePoint) []AggregatePoint {
+	if len(newPoints) == 0 {
+		return existing
+	}
+	if len(existing) == 0 {
+		return newPoints
+	}
+
+	merged := make([]AggregatePoint, 0, len(existing)+len(newPoints))
+
+	i, j := 0, 0
+	for i < len(existing) && j < len(newPoints) {
+		switch {
+		case existing[i].Timestamp.Before(newPoints[j].Timestamp):
+			merged = append(merged, existing[i])
+			i++
+		case newPoints[j].Timestamp.Before(existing[i].Timestamp):
+			merged = append(merged, newPoints[j])
+			j++
+		default:
+			combined := existing[i]
+			combined.merge(newPoints[j])
+			merged = append(merged, combined)
+			i++
+			j++
+		}
+	}
+	merged = append(merged, existing[i:]...)
+	merged = append(merged, newPoints[j:]...)
+
+	return merged
+}
+
+func (a *AggregatePoint) addSample(temperature float64) {
+	if a.Count == 0 {
+		a.Min = temperature
+		a.Max = temperature
+		a.Mean = temperature
+		a.Count = 1
+		return
+	}
+	if temperature < a.Min {
+		a.Min = temperature
+	}
+	if temperature > a.Max {
+		a.Max = temperature
+	}
+	a.Mean = (a.Mean*float64(a.Count) + temperature) / float64(a.Count+1)
+	a.Count++
+}
+
+func (a *AggregatePoint) merge(other AggregatePoint) {
+	if other.Count == 0 {
+		return
+	}
+	if a.Count == 0 {
+		*a = other
+		return
+	}
+	if other.Min < a.Min {
+		a.Min = other.Min
+	}
+	if other.Max > a.Max {
+		a.Max = other.Max
+	}
+	totalCount := a.Count + other.Count
+	a.Mean = (a.Mean*float64(a.Count) + other.Mean*float64(other.Count)) / float64(totalCount)
+	a.Count = totalCount
 }
 
 func (server *TemperatureApi) PostTemperature(sensorID, deviceType string, sampleTime time.Time, temperature float64) error {
@@ -54,24 +387,67 @@ func (server *TemperatureApi) PostTemperature(sensorID, deviceType string, sampl
 	// this could be problematic if we had an unreliable sensor that was sending
 	// different device types for the same sensor ID though...
 	deviceData.DeviceType = deviceType
-	deviceData.DataPoints = append(deviceData.DataPoints, DataPoint{
-		Timestamp:   sampleTime,
-		Temperature: temperature,
-	})
 
-	// sort data by timestamp
-	// note that this might not be the most efficient way to keep this data sorted,
-	// but with this approach we sort once on insert, vs. sorting on each GET
-	sort.Slice(deviceData.DataPoints, func(i, j int) bool {
-		return deviceData.DataPoints[i].Timestamp.Before(deviceData.DataPoints[j].Timestamp)
-	})
+	// Append into the device's store: in-order points land straight in
+	// the sorted cold segment in O(1); out-of-order ones go to the hot
+	// tail and get merge-sorted in later, instead of a full sort.Slice
+	// on every single insert.
+	store := deviceData.store()
+	store.Append(DataPoint{Timestamp: sampleTime, Temperature: temperature})
+
+	// enforce MaxDataPoints by evicting the oldest raw point(s); the
+	// compactor would have folded them into the 1-minute tier eventually
+	// anyway, but a device exceeding the cap needs to be trimmed now.
+	store.Trim(MaxDataPoints)
+	deviceData.storeInto(store)
 
 	server.Data[sensorID] = deviceData
 
+	if server.wal != nil {
+		if err := server.wal.append(sensorID, deviceType, sampleTime, temperature); err != nil {
+			return fmt.Errorf("appending to WAL: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func (server *TemperatureApi) GetTemperature(deviceID string, startTime, endTime time.Time) (ResponseBody, error) {
+// Checkpoint writes a compact snapshot of the current in-memory state
+// and truncates the WAL segments it makes obsolete, analogous to a
+// TSDB head checkpoint. It's a no-op if the server wasn't opened with
+// InitWithWAL.
+func (server *TemperatureApi) Checkpoint() error {
+	if server.wal == nil {
+		return nil
+	}
+
+	// Hold the lock until the old segments are removed, so no write can
+	// land in a segment after the snapshot was taken and then be deleted.
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	snapshot := make(map[string]DeviceData, len(server.Data))
+	for deviceID, data := range server.Data {
+		store := data.store()
+		data.storeInto(deviceStore{cold: store.Points()})
+		server.Data[deviceID] = data
+		snapshot[deviceID] = data
+	}
+
+	return server.wal.checkpoint(snapshot)
+}
+
+// GetTemperature returns the data points for deviceID within
+// [startTime, endTime]. An optional Resolution selects which storage
+// tier to read from; if omitted, ResolutionRaw is used. Requesting
+// ResolutionOneMinute or ResolutionOneHour reads the corresponding
+// downsampled rollup tier (maintained by the background compactor)
+// instead of raw samples, so a long time range doesn't require dragging
+// back and re-processing millions of raw points. Rollup buckets are
+// surfaced as DataPoints using each bucket's mean temperature. For
+// ResolutionRaw, any points still sitting in the device's hot tail (see
+// deviceStore) are merged into the sorted cold segment first.
+func (server *TemperatureApi) GetTemperature(deviceID string, startTime, endTime time.Time, resolution ...Resolution) (ResponseBody, error) {
 
 	// validate input data
 	if deviceID == "" {
@@ -87,6 +463,15 @@ func (server *TemperatureApi) GetTemperature(deviceID string, startTime, endTime
 		return ResponseBody{}, errors.New("end time cannot be before start time")
 	}
 
+	res := ResolutionRaw
+	if len(resolution) > 0 {
+		res = resolution[0]
+	}
+
+	if res == ResolutionRaw {
+		server.mergeDeviceTail(deviceID)
+	}
+
 	// Read-lock the data structure for concurrent safety
 	server.mutex.RLock()
 	defer server.mutex.RUnlock()
@@ -94,6 +479,10 @@ func (server *TemperatureApi) GetTemperature(deviceID string, startTime, endTime
 	// retrieve and slice data
 	data := server.Data[deviceID]
 
+	if res != ResolutionRaw {
+		return aggregateResponseBody(deviceID, data, res, startTime, endTime), nil
+	}
+
 	// return empty data if the device exists but there are no
 	// temperature data points for it
 	// (this shouldn't be possible, but just in case)
@@ -142,3 +531,31 @@ func (server *TemperatureApi) GetTemperature(deviceID string, startTime, endTime
 		},
 	}, nil
 }
+
+// aggregateResponseBody builds a response from a rollup tier, converting
+// each AggregatePoint in range into a DataPoint carrying its mean.
+func aggregateResponseBody(deviceID string, data DeviceData, res Resolution, startTime, endTime time.Time) ResponseBody {
+	var tier []AggregatePoint
+	switch res {
+	case ResolutionOneMinute:
+		tier = data.OneMinute
+	case ResolutionOneHour:
+		tier = data.OneHour
+	}
+
+	points := make([]DataPoint, 0, len(tier))
+	for _, bucket := range tier {
+		if bucket.Timestamp.Before(startTime) || bucket.Timestamp.After(endTime) {
+			continue
+		}
+		points = append(points, DataPoint{Timestamp: bucket.Timestamp, Temperature: bucket.Mean})
+	}
+
+	return ResponseBody{
+		DeviceID: deviceID,
+		DeviceData: DeviceData{
+			DeviceType: data.DeviceType,
+			DataPoints: points,
+		},
+	}
+}
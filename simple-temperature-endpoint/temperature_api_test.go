@@ -197,3 +197,99 @@ func TestGetTemperature_Ranges(t *testing.T) {
 	assert.Len(t, actual.DataPoints, 1)
 	assert.Equal(t, sampleData[len(sampleData)-1], actual.DataPoints[0])
 }
+
+func TestPostTemperature_EnforcesMaxDataPoints(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.Init()
+	deviceID := "device1"
+	startTime := time.Now()
+
+	// Act - insert one more point than MaxDataPoints allows
+	for i := 0; i < MaxDataPoints+1; i++ {
+		err := target.PostTemperature(deviceID, "temperature_sensor", startTime.Add(time.Duration(i)*time.Second), 20.0)
+		require.NoError(t, err)
+	}
+
+	// Assert - the oldest point was evicted, so the device never exceeds the cap
+	data := target.Data[deviceID]
+	assert.Len(t, data.DataPoints, MaxDataPoints)
+	assert.Equal(t, startTime.Add(time.Second), data.DataPoints[0].Timestamp)
+}
+
+func TestCompact_FoldsAgedRawPointsIntoOneMinuteTier(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.InitWithRetention(RetentionConfig{
+		RawRetention:       time.Hour,
+		OneMinuteRetention: 24 * time.Hour,
+		OneHourRetention:   365 * 24 * time.Hour,
+		// CompactInterval is 0 so the background goroutine never runs;
+		// this test drives compact() directly instead.
+	})
+	defer target.Stop()
+
+	deviceID := "device1"
+	// truncated so that now-2h and now-2h+1s always share a minute bucket
+	now := time.Now().Truncate(time.Minute)
+
+	// two samples in the same minute bucket, two hours old
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", now.Add(-2*time.Hour), 10.0))
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", now.Add(-2*time.Hour+time.Second), 20.0))
+	// one recent sample that should stay raw
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", now, 30.0))
+
+	// Act
+	target.compact(now)
+
+	// Assert
+	data := target.Data[deviceID]
+	require.Len(t, data.DataPoints, 1)
+	assert.Equal(t, 30.0, data.DataPoints[0].Temperature)
+
+	require.Len(t, data.OneMinute, 1)
+	assert.Equal(t, 10.0, data.OneMinute[0].Min)
+	assert.Equal(t, 20.0, data.OneMinute[0].Max)
+	assert.Equal(t, 15.0, data.OneMinute[0].Mean)
+	assert.Equal(t, 2, data.OneMinute[0].Count)
+
+	// Act - query the 1-minute tier through GetTemperature
+	actual, err := target.GetTemperature(deviceID, now.Add(-3*time.Hour), now.Add(-time.Hour), ResolutionOneMinute)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, actual.DataPoints, 1)
+	assert.Equal(t, 15.0, actual.DataPoints[0].Temperature)
+}
+
+func TestCompact_KeepsOneMinuteTierSortedAcrossLateArrivals(t *testing.T) {
+	// Arrange
+	target := TemperatureApi{}
+	target.InitWithRetention(RetentionConfig{
+		RawRetention:       time.Hour,
+		OneMinuteRetention: 24 * time.Hour,
+		OneHourRetention:   365 * 24 * time.Hour,
+		// CompactInterval is 0 so the background goroutine never runs;
+		// this test drives compact() directly instead.
+	})
+	defer target.Stop()
+
+	deviceID := "device1"
+	now := time.Now()
+
+	// a point ages out and gets folded into OneMinute first...
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", now.Add(-3*time.Hour), 10.0))
+	target.compact(now)
+
+	// ...then a late out-of-order arrival, older than everything folded
+	// so far, sits in raw storage until the next compaction folds it in
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", now.Add(-4*time.Hour), 20.0))
+	target.compact(now.Add(2 * time.Hour))
+
+	// Assert - OneMinute stays sorted by Timestamp, oldest bucket first
+	data := target.Data[deviceID]
+	require.Len(t, data.OneMinute, 2)
+	assert.True(t, data.OneMinute[0].Timestamp.Before(data.OneMinute[1].Timestamp))
+	assert.Equal(t, 20.0, data.OneMinute[0].Mean)
+	assert.Equal(t, 10.0, data.OneMinute[1].Mean)
+}
@@ -0,0 +1,475 @@
+package temperature_api
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// walSegmentSizeThreshold is the size at which a WAL segment is rotated
+// in favor of a new one, bounding how much a single file can grow.
+const walSegmentSizeThreshold = 128 * 1024 * 1024 // 128 MiB
+
+const (
+	walSegmentPrefix    = "wal-"
+	walSegmentExt       = ".log"
+	walCheckpointPrefix = "checkpoint-"
+	walCheckpointExt    = ".snap"
+)
+
+// CorruptionErr is logged when a WAL record fails its CRC32 check or is
+// otherwise malformed during replay; it is not returned from replay. The
+// record is skipped; everything else in the segment is still replayed.
+type CorruptionErr struct {
+	Segment string
+	Offset  int64
+	Err     error
+}
+
+func (e *CorruptionErr) Error() string {
+	return fmt.Sprintf("corrupt WAL record in %s at offset %d: %s", e.Segment, e.Offset, e.Err)
+}
+
+func (e *CorruptionErr) Unwrap() error {
+	return e.Err
+}
+
+// wal is a segmented, length-prefixed, CRC-checked write-ahead log of
+// PostTemperature calls, rooted at a single directory.
+type wal struct {
+	dir string
+
+	mu          sync.Mutex
+	current     *os.File
+	currentSize int64
+	nextIndex   int
+}
+
+// openWAL opens (creating if necessary) the WAL directory at dir,
+// replays every existing segment to rebuild in-memory device data, and
+// returns a wal ready to accept further appends.
+func openWAL(dir string) (*wal, map[string]DeviceData, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, fmt.Errorf("creating WAL directory: %w", err)
+	}
+
+	data, lastIndex, err := replay(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := &wal{dir: dir, nextIndex: lastIndex + 1}
+	if err := w.rotate(); err != nil {
+		return nil, nil, err
+	}
+
+	return w, data, nil
+}
+
+// replay rebuilds device data from the latest checkpoint (if any) plus
+// every WAL segment newer than it, in order. Records that fail their
+// CRC32 check are skipped and logged as a *CorruptionErr rather than
+// aborting the whole replay.
+func replay(dir string) (map[string]DeviceData, int, error) {
+	data, fromIndex, err := loadLatestCheckpoint(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	lastIndex := fromIndex - 1
+	for _, seg := range segments {
+		if seg.index < fromIndex {
+			continue
+		}
+		if err := replaySegment(seg.path, data); err != nil {
+			return nil, 0, err
+		}
+		lastIndex = seg.index
+	}
+
+	// Records are replayed in the order they were written, not
+	// necessarily timestamp order, so do one sort pass per device now
+	// rather than paying for it on every record.
+	for deviceID, deviceData := range data {
+		sort.Slice(deviceData.DataPoints, func(i, j int) bool {
+			return deviceData.DataPoints[i].Timestamp.Before(deviceData.DataPoints[j].Timestamp)
+		})
+		data[deviceID] = deviceData
+	}
+
+	return data, lastIndex, nil
+}
+
+func replaySegment(path string, data map[string]DeviceData) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("opening segment %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var offset int64
+	for {
+		record, n, err := readRecord(f)
+		if err == io.EOF {
+			return nil
+		}
+
+		var corrupt *recordCorruptionErr
+		if errors.As(err, &corrupt) {
+			// This one record is malformed: skip it, but keep replaying
+			// the rest of the segment.
+			corruptionErr := &CorruptionErr{Segment: path, Offset: offset, Err: corrupt}
+			log.Printf("%s", corruptionErr)
+			offset += int64(n)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading segment %s at offset %d: %w", path, offset, err)
+		}
+
+		deviceData := data[record.deviceID]
+		deviceData.DeviceType = record.deviceType
+		deviceData.DataPoints = append(deviceData.DataPoints, DataPoint{
+			Timestamp:   time.Unix(0, record.timestampNanos),
+			Temperature: record.temperature,
+		})
+		data[record.deviceID] = deviceData
+
+		offset += int64(n)
+	}
+}
+
+type walRecord struct {
+	deviceID       string
+	deviceType     string
+	timestampNanos int64
+	temperature    float64
+}
+
+// record layout: uint32 total length, then deviceID (uint16 length +
+// bytes), deviceType (uint16 length + bytes), int64 timestamp, float64
+// temperature, uint32 CRC32 over everything after the length prefix.
+func encodeRecord(deviceID, deviceType string, sampleTime time.Time, temperature float64) []byte {
+	body := make([]byte, 0, 2+len(deviceID)+2+len(deviceType)+8+8)
+	body = appendUint16String(body, deviceID)
+	body = appendUint16String(body, deviceType)
+	body = binary.BigEndian.AppendUint64(body, uint64(sampleTime.UnixNano()))
+	body = binary.BigEndian.AppendUint64(body, math.Float64bits(temperature))
+
+	checksum := crc32.ChecksumIEEE(body)
+	body = binary.BigEndian.AppendUint32(body, checksum)
+
+	out := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(out, uint32(len(body)))
+	copy(out[4:], body)
+
+	return out
+}
+
+// recordCorruptionErr marks a single record as malformed (bad checksum
+// or truncated fields inside an otherwise length-prefixed record) as
+// opposed to a fatal I/O error - replaySegment logs and skips these
+// rather than aborting the rest of the segment.
+type recordCorruptionErr struct {
+	reason string
+}
+
+func (e *recordCorruptionErr) Error() string {
+	return e.reason
+}
+
+// readRecord reads one length-prefixed record from r. A nil *walRecord
+// with a *recordCorruptionErr means the record was malformed and was
+// skipped; n is still the number of bytes consumed so the caller can
+// advance its offset.
+func readRecord(r io.Reader) (*walRecord, int, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, 0, err
+	}
+	recordLen := binary.BigEndian.Uint32(lenBuf[:])
+
+	body := make([]byte, recordLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, 0, fmt.Errorf("short record: %w", err)
+	}
+	total := 4 + int(recordLen)
+
+	if len(body) < 4 {
+		return nil, total, &recordCorruptionErr{reason: "record shorter than its CRC32 footer"}
+	}
+	payload, wantChecksum := body[:len(body)-4], binary.BigEndian.Uint32(body[len(body)-4:])
+	if crc32.ChecksumIEEE(payload) != wantChecksum {
+		return nil, total, &recordCorruptionErr{reason: "CRC32 checksum mismatch"}
+	}
+
+	deviceID, rest, err := readUint16String(payload)
+	if err != nil {
+		return nil, total, &recordCorruptionErr{reason: fmt.Sprintf("malformed device ID field: %s", err)}
+	}
+	deviceType, rest, err := readUint16String(rest)
+	if err != nil {
+		return nil, total, &recordCorruptionErr{reason: fmt.Sprintf("malformed device type field: %s", err)}
+	}
+	if len(rest) != 16 {
+		return nil, total, &recordCorruptionErr{reason: fmt.Sprintf("expected 16 bytes of timestamp/temperature, got %d", len(rest))}
+	}
+
+	return &walRecord{
+		deviceID:       deviceID,
+		deviceType:     deviceType,
+		timestampNanos: int64(binary.BigEndian.Uint64(rest[0:8])),
+		temperature:    math.Float64frombits(binary.BigEndian.Uint64(rest[8:16])),
+	}, total, nil
+}
+
+func appendUint16String(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+func readUint16String(buf []byte) (string, []byte, error) {
+	if len(buf) < 2 {
+		return "", nil, fmt.Errorf("truncated string length")
+	}
+	n := int(binary.BigEndian.Uint16(buf[:2]))
+	buf = buf[2:]
+	if len(buf) < n {
+		return "", nil, fmt.Errorf("truncated string value")
+	}
+	return string(buf[:n]), buf[n:], nil
+}
+
+// append writes one record for a successful PostTemperature call,
+// rotating to a new segment first if the current one has grown past
+// walSegmentSizeThreshold.
+func (w *wal) append(deviceID, deviceType string, sampleTime time.Time, temperature float64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.currentSize >= walSegmentSizeThreshold {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	record := encodeRecord(deviceID, deviceType, sampleTime, temperature)
+	n, err := w.current.Write(record)
+	if err != nil {
+		return fmt.Errorf("writing WAL record: %w", err)
+	}
+	w.currentSize += int64(n)
+
+	return nil
+}
+
+// rotate closes the current segment (if any) and opens a new, empty one.
+func (w *wal) rotate() error {
+	if w.current != nil {
+		if err := w.current.Close(); err != nil {
+			return fmt.Errorf("closing WAL segment: %w", err)
+		}
+	}
+
+	path := filepath.Join(w.dir, segmentName(w.nextIndex))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("creating WAL segment %s: %w", path, err)
+	}
+
+	w.current = f
+	w.currentSize = 0
+	w.nextIndex++
+
+	return nil
+}
+
+// checkpoint writes a gob-encoded snapshot of data and removes every WAL
+// segment it makes obsolete (i.e. every segment that existed before the
+// checkpoint was taken).
+func (w *wal) checkpoint(data map[string]DeviceData) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// The currently-open segment is still being appended to, so it can't
+	// be deleted as obsolete. Seal it with a rotate first - after this,
+	// obsoleteUpTo refers to a fully-written, safe-to-remove segment, and
+	// subsequent appends land in a fresh one.
+	obsoleteUpTo := w.nextIndex - 1
+	if err := w.rotate(); err != nil {
+		return fmt.Errorf("rotating WAL before checkpoint: %w", err)
+	}
+	path := filepath.Join(w.dir, checkpointName(obsoleteUpTo))
+
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("creating checkpoint: %w", err)
+	}
+	if err := gob.NewEncoder(f).Encode(data); err != nil {
+		f.Close()
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing checkpoint: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("finalizing checkpoint: %w", err)
+	}
+
+	segments, err := listSegments(w.dir)
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		if seg.index <= obsoleteUpTo {
+			if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("removing obsolete segment %s: %w", seg.path, err)
+			}
+		}
+	}
+
+	return removeOlderCheckpoints(w.dir, obsoleteUpTo)
+}
+
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.current == nil {
+		return nil
+	}
+	return w.current.Close()
+}
+
+func loadLatestCheckpoint(dir string) (map[string]DeviceData, int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]DeviceData), 0, nil
+		}
+		return nil, 0, fmt.Errorf("reading WAL directory: %w", err)
+	}
+
+	latestIndex := -1
+	latestName := ""
+	for _, entry := range entries {
+		idx, ok := checkpointIndex(entry.Name())
+		if ok && idx > latestIndex {
+			latestIndex = idx
+			latestName = entry.Name()
+		}
+	}
+
+	if latestName == "" {
+		return make(map[string]DeviceData), 0, nil
+	}
+
+	f, err := os.Open(filepath.Join(dir, latestName))
+	if err != nil {
+		return nil, 0, fmt.Errorf("opening checkpoint %s: %w", latestName, err)
+	}
+	defer f.Close()
+
+	data := make(map[string]DeviceData)
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		return nil, 0, fmt.Errorf("decoding checkpoint %s: %w", latestName, err)
+	}
+
+	return data, latestIndex + 1, nil
+}
+
+func removeOlderCheckpoints(dir string, keepIndex int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading WAL directory: %w", err)
+	}
+	for _, entry := range entries {
+		idx, ok := checkpointIndex(entry.Name())
+		if ok && idx < keepIndex {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("removing stale checkpoint %s: %w", entry.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+type segmentFile struct {
+	index int
+	path  string
+}
+
+func listSegments(dir string) ([]segmentFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading WAL directory: %w", err)
+	}
+
+	var segments []segmentFile
+	for _, entry := range entries {
+		idx, ok := segmentIndex(entry.Name())
+		if ok {
+			segments = append(segments, segmentFile{index: idx, path: filepath.Join(dir, entry.Name())})
+		}
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i].index < segments[j].index })
+
+	return segments, nil
+}
+
+func segmentName(index int) string {
+	return fmt.Sprintf("%s%020d%s", walSegmentPrefix, index, walSegmentExt)
+}
+
+func segmentIndex(name string) (int, bool) {
+	if !strings.HasPrefix(name, walSegmentPrefix) || !strings.HasSuffix(name, walSegmentExt) {
+		return 0, false
+	}
+	n := strings.TrimSuffix(strings.TrimPrefix(name, walSegmentPrefix), walSegmentExt)
+	idx, err := strconv.Atoi(n)
+	if err != nil {
+		return 0, false
+	}
+	return idx, true
+}
+
+func checkpointName(index int) string {
+	return fmt.Sprintf("%s%020d%s", walCheckpointPrefix, index, walCheckpointExt)
+}
+
+func checkpointIndex(name string) (int, bool) {
+	if !strings.HasPrefix(name, walCheckpointPrefix) || !strings.HasSuffix(name, walCheckpointExt) {
+		return 0, false
+	}
+	n := strings.TrimSuffix(strings.TrimPrefix(name, walCheckpointPrefix), walCheckpointExt)
+	idx, err := strconv.Atoi(n)
+	if err != nil {
+		return 0, false
+	}
+	return idx, true
+}
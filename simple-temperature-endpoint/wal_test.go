@@ -0,0 +1,142 @@
+package temperature_api
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitWithWAL_ReplaysAfterRestart(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	deviceID := "device1"
+	sampleTime := time.Now()
+
+	target := TemperatureApi{}
+	require.NoError(t, target.InitWithWAL(dir, DefaultRetentionConfig()))
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", sampleTime, 21.5))
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", sampleTime.Add(time.Second), 22.0))
+	target.Stop()
+
+	// Act - simulate a restart by opening a fresh server over the same dir
+	restarted := TemperatureApi{}
+	require.NoError(t, restarted.InitWithWAL(dir, DefaultRetentionConfig()))
+	defer restarted.Stop()
+
+	// Assert
+	data := restarted.Data[deviceID]
+	require.Len(t, data.DataPoints, 2)
+	assert.Equal(t, 21.5, data.DataPoints[0].Temperature)
+	assert.Equal(t, 22.0, data.DataPoints[1].Temperature)
+}
+
+func TestCheckpoint_TruncatesObsoleteSegments(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	target := TemperatureApi{}
+	require.NoError(t, target.InitWithWAL(dir, DefaultRetentionConfig()))
+	defer target.Stop()
+
+	require.NoError(t, target.PostTemperature("device1", "temperature_sensor", time.Now(), 21.5))
+
+	// Act
+	require.NoError(t, target.Checkpoint())
+
+	// Assert - the segment that predates the checkpoint is gone, but a
+	// checkpoint snapshot now exists in its place
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var sawCheckpoint bool
+	for _, entry := range entries {
+		if _, ok := checkpointIndex(entry.Name()); ok {
+			sawCheckpoint = true
+		}
+		// segment 0 was open when the point was posted, so the checkpoint
+		// makes it obsolete
+		if idx, ok := segmentIndex(entry.Name()); ok {
+			assert.NotEqual(t, 0, idx, "obsolete segment %s was not removed", entry.Name())
+		}
+	}
+	assert.True(t, sawCheckpoint, "expected a checkpoint snapshot file in %s", dir)
+}
+
+func TestCheckpoint_SurvivesRestartAlongsideLaterWrites(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	deviceID := "device1"
+	sampleTime := time.Now()
+
+	target := TemperatureApi{}
+	require.NoError(t, target.InitWithWAL(dir, DefaultRetentionConfig()))
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", sampleTime, 21.5))
+	require.NoError(t, target.Checkpoint())
+
+	// Act - post again after the checkpoint, then restart. If Checkpoint
+	// deleted the segment still being appended to, this later write would
+	// be lost.
+	require.NoError(t, target.PostTemperature(deviceID, "temperature_sensor", sampleTime.Add(time.Second), 22.0))
+	target.Stop()
+
+	restarted := TemperatureApi{}
+	require.NoError(t, restarted.InitWithWAL(dir, DefaultRetentionConfig()))
+	defer restarted.Stop()
+
+	// Assert - both the checkpointed point and the point written after it
+	// survive the restart
+	data := restarted.Data[deviceID]
+	require.Len(t, data.DataPoints, 2)
+	assert.Equal(t, 21.5, data.DataPoints[0].Temperature)
+	assert.Equal(t, 22.0, data.DataPoints[1].Temperature)
+}
+
+func TestCorruptionErr_ErrorIncludesTheActualReason(t *testing.T) {
+	// Arrange - a well-formed record with its last byte (part of the
+	// CRC32 footer) flipped
+	record := encodeRecord("device1", "temperature_sensor", time.Now(), 21.5)
+	record[len(record)-1] ^= 0xFF
+
+	// Act
+	_, _, err := readRecord(bytes.NewReader(record))
+	require.Error(t, err)
+	corruptionErr := &CorruptionErr{Segment: "wal-0.log", Offset: 0, Err: err}
+
+	// Assert - the rendered error names the actual failure instead of
+	// a nil placeholder
+	assert.Contains(t, corruptionErr.Error(), "checksum")
+	assert.NotContains(t, corruptionErr.Error(), "<nil>")
+}
+
+func TestReplaySegment_SkipsCorruptRecords(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	target := TemperatureApi{}
+	require.NoError(t, target.InitWithWAL(dir, DefaultRetentionConfig()))
+	require.NoError(t, target.PostTemperature("device1", "temperature_sensor", time.Now(), 21.5))
+	target.Stop()
+
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, segments)
+
+	// corrupt the last byte of the segment's CRC
+	f, err := os.OpenFile(segments[len(segments)-1].path, os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	stat, err := f.Stat()
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xFF}, stat.Size()-1)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// Act
+	restarted := TemperatureApi{}
+	require.NoError(t, restarted.InitWithWAL(dir, DefaultRetentionConfig()))
+	defer restarted.Stop()
+
+	// Assert - the corrupt record was skipped rather than replayed
+	assert.Empty(t, restarted.Data["device1"].DataPoints)
+}